@@ -1,8 +1,13 @@
 package cb
 
 import (
+	"container/list"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-mego/mego"
@@ -11,6 +16,10 @@ import (
 var (
 	// ErrOpenState 表示斷路器處於開啟狀態，所有請求都被拒絕。
 	ErrOpenState = errors.New("circuitbreaker: the circuit breaker is open")
+	// ErrTooManyRequests 表示斷路器處於半開放狀態，且目前通過的探測請求數已達到 `MaxRequests` 上限。
+	ErrTooManyRequests = errors.New("circuitbreaker: too many requests in half-open state")
+	// ErrBreakerNotFound 表示管理處理器找不到請求中指定名稱的斷路器，或是收到不支援的請求方法、動作。
+	ErrBreakerNotFound = errors.New("circuitbreaker: breaker not found")
 	// DefaultFailureStatuses 是預設的錯誤狀態碼清單，可用於自動錯誤偵測上。
 	DefaultFailureStatuses = []int{
 		http.StatusInternalServerError,   // 500
@@ -35,6 +44,8 @@ const (
 
 // Counts 是斷路器的計數狀態。
 type Counts struct {
+	// Requests 是總請求次數，也就是 `TotalSuccesses` 與 `TotalFailures` 的總和。
+	Requests int
 	// TotalSuccesses 是總共的成功次數。
 	TotalSuccesses int
 	// TotalFailures 是總共的失敗次數。
@@ -49,7 +60,15 @@ type Counts struct {
 type Options struct {
 	// Name 是斷路器的名稱。
 	Name string
-	// FailureStatuses 是自動失敗 HTTP 狀態碼，當回應的狀態碼於此清單內會自動視為失敗而計次。
+	// MaxRequests 是斷路器處於半開放狀態時，允許通過的探測請求數上限。
+	// 超過此上限的請求會直接以 `ErrTooManyRequests` 短路，且必須連續成功達到此數量，斷路器才會回到關閉狀態。
+	// 預設為 1。
+	MaxRequests int
+	// MaxKeys 是 `NewKeyed` 底下，允許同時存在的鍵值分流斷路器數量上限。
+	// 超過此上限時，最久未被使用的鍵值斷路器會被淘汰，避免惡意的鍵值無限制地佔用記憶體。
+	// 預設為 1000。
+	MaxKeys int
+	// FailureStatuses 是自動失敗 HTTP 狀態碼，當回應的狀態碼於此清單內會自動視為失敗而計次。
 	// `DefaultFailureStatuses` 是預設的 5xx 伺服器錯誤碼清單，若不想使用此功能可傳入 `EmptyFailureStatuses` 空狀態碼清單。
 	FailureStatuses []int
 	// Interval 是斷路器的循環週期。在斷路器復歸時經過此秒數後會重設整個斷路器資訊。
@@ -60,8 +79,13 @@ type Options struct {
 	Timeout time.Duration
 	// OnTrip 會在每次經過斷路器時所觸發，此函式會接收上下文建構體與目前的計次狀態。
 	// 當此函式回傳 `true` 時，斷路器就會被開啟而拒絕接下來的請求。
-	// 此函式預設為連續失敗 5 次就斷路。
+	// 透過 `Execute`/`Do` 呼叫時 `ctx` 會是 `nil`，若此斷路器同時作為 HTTP 中介層使用，
+	// 自訂的 `OnTrip` 必須能夠容忍 `ctx` 為 `nil`。此函式預設為連續失敗 5 次就斷路（不會碰觸 `ctx`）。
 	OnTrip func(ctx *mego.Context, counts Counts) bool
+	// ReadyToTrip 與 `OnTrip` 類似，但接收的是最近一段滾動窗口內的計次狀態，
+	// 讓開發者可以依照近期的失敗「比率」而非僅僅連續次數來決定是否斷路，
+	// 例如 `counts.Requests >= 20 && counts.TotalFailures >= counts.Requests*6/10`。此欄位預設不啟用。
+	ReadyToTrip func(counts Counts) bool
 	// OnStateChange 會在斷路器的狀態變更時呼叫。
 	OnStateChange func(name string, from State, to State)
 }
@@ -84,14 +108,37 @@ func (s State) String() string {
 
 // New 會建立一個斷路器。
 func New(options ...*Options) mego.HandlerFunc {
+	return handlerFor(NewBreaker(options...))
+}
+
+// NewBreaker 會建立一個獨立的斷路器並回傳其實例，讓開發者可以直接透過 `Execute`/`Do` 保護非 HTTP 的依賴，
+// 不需要先掛載對應的 HTTP 中介層。它與 `New` 共用同一套預設值與 `defaultRegistry`，因此一樣能透過
+// `AdminHandler` 查詢與操作。注意：不要把同一個實例既掛載為路由中介層、又在該路由處理函式內巢狀呼叫
+// 它的 `Execute`/`Do`——中介層的放行已經佔用了這次請求的半開放探測名額並計次一次，巢狀呼叫會重複計次，
+// 且可能讓名額提前耗盡而被 `ErrTooManyRequests` 擋下。若要在路由處理函式內保護另一個出站依賴，
+// 請改用另一個透過 `NewBreaker` 獨立建立的斷路器。
+func NewBreaker(options ...*Options) *Breaker {
 	o := &Options{
 		FailureStatuses: DefaultFailureStatuses,
 	}
 	if len(options) > 0 {
 		o = options[0]
 	}
+	applyDefaults(o)
+
+	b := newBreaker(o, o.Name)
+	defaultRegistry.register(b)
+	return b
+}
+
+// anonymousBreakerSeq 為未指定 `Name` 的斷路器產生遞增序號，
+// 確保它們在 `defaultRegistry` 中各自擁有獨立的名稱，不會互相覆蓋彼此。
+var anonymousBreakerSeq uint64
+
+// applyDefaults 會將 `Options` 中未設置的欄位填入預設值。
+func applyDefaults(o *Options) {
 	if o.Name == "" {
-		o.Name = "CircuitBreaker"
+		o.Name = fmt.Sprintf("CircuitBreaker-%d", atomic.AddUint64(&anonymousBreakerSeq, 1))
 	}
 	if o.Timeout.Seconds() == 0 {
 		o.Timeout = time.Second * 60
@@ -102,37 +149,33 @@ func New(options ...*Options) mego.HandlerFunc {
 	if o.OnTrip == nil {
 		o.OnTrip = tripper
 	}
-	b := &Breaker{
-		name:         o.Name,
+	if o.MaxRequests == 0 {
+		o.MaxRequests = 1
+	}
+	if o.MaxKeys == 0 {
+		o.MaxKeys = 1000
+	}
+}
+
+// newBreaker 會依照選項建立一個新的斷路器實例，name 會作為此實例的名稱（用於登錄表與管理介面）。
+func newBreaker(o *Options, name string) *Breaker {
+	return &Breaker{
+		name:         name,
 		options:      o,
 		counts:       &Counts{},
 		state:        StateClosed,
 		lastInterval: time.Now(),
+		bucketSpan:   o.Interval / bucketCount,
+		bucketAt:     time.Now(),
 	}
+}
+
+// handlerFor 會建立一個將請求交由 b 把關的 `mego.HandlerFunc`，供 `New` 與 `NewKeyed` 共用。
+func handlerFor(b *Breaker) mego.HandlerFunc {
 	return func(c *mego.Context) {
-		// 如果斷路器處於開啟狀態。
-		if b.State() == StateOpen {
-			// 要是上次失敗的時間已經超過我們所設定的逾時時間，
-			// 那麼就給斷路器一次機會，回到半開放狀態。
-			if time.Since(b.lastFailure) >= b.options.Timeout {
-				b.state = StateHalfOpen
-			}
-		}
-		// 如果斷路器處於關閉狀態。
-		if b.State() == StateClosed {
-			// 要是上次失敗的時間已經超過了我們所設定的週期時間，
-			// 那麼就重設斷路器的所有資訊，假裝先前的錯誤不曾發生過。
-			if time.Since(b.lastInterval) >= b.options.Interval {
-				b.reset()
-			}
-			// 呼叫過路函式，讓開發者決定是否要開啟斷路器。
-			if b.options.OnTrip(c, *b.counts) {
-				b.state = StateOpen
-			}
-		}
-		// 如果經過前面那些條件，斷路器還是開啟的話就回傳 HTTP 內部伺服器錯誤狀態碼。
-		if b.State() == StateOpen {
-			c.AbortWithError(http.StatusServiceUnavailable, ErrOpenState)
+		generation, isHalfOpenProbe, err := b.beforeRequest(c)
+		if err != nil {
+			c.AbortWithError(http.StatusServiceUnavailable, err)
 			return
 		}
 
@@ -145,23 +188,103 @@ func New(options ...*Options) mego.HandlerFunc {
 			for _, v := range b.options.FailureStatuses {
 				// 如果回應的狀態碼屬於錯誤狀態碼，就像斷路器表明此次請求失敗並計次遞加。
 				if c.Writer.Status() == v {
-					b.fail()
+					b.afterRequest(generation, isHalfOpenProbe, true)
 					return
 				}
 			}
 			// 不然就算此次請求成功。
-			b.success()
+			b.afterRequest(generation, isHalfOpenProbe, false)
 		}()
 	}
 }
 
+// NewKeyed 會建立一個依照 keyFn 所回傳的鍵值分流的斷路器中介層，讓同一條路由可以依照不同的上游主機、
+// 租戶 ID 或路徑參數，各自擁有獨立的斷路器，彼此的失敗不會互相影響。每個鍵值底下的斷路器都是惰性建立、重複使用，
+// 並繼承同一份 `Options`（`Interval`、`Timeout`、`OnTrip`、`FailureStatuses` 等），但各自擁有獨立的計數器與狀態，
+// 也同樣能透過 `AdminHandler` 被查詢與操作。
+func NewKeyed(keyFn func(c *mego.Context) string, options ...*Options) mego.HandlerFunc {
+	o := &Options{
+		FailureStatuses: DefaultFailureStatuses,
+	}
+	if len(options) > 0 {
+		o = options[0]
+	}
+	applyDefaults(o)
+
+	kb := newKeyedBreakers(o)
+	return func(c *mego.Context) {
+		handlerFor(kb.get(keyFn(c)))(c)
+	}
+}
+
+// keyedBreakerEntry 是 `keyedBreakers` 中 LRU 清單的節點內容。
+type keyedBreakerEntry struct {
+	key     string
+	breaker *Breaker
+}
+
+// keyedBreakers 會依照鍵值惰性建立並快取斷路器，並在鍵值數量超過 `options.MaxKeys` 時，
+// 以 LRU 淘汰最久未被使用的那一個，避免惡意的鍵值無限制地佔用記憶體。
+type keyedBreakers struct {
+	mu      sync.Mutex
+	options *Options
+	items   map[string]*list.Element
+	order   *list.List
+}
+
+// newKeyedBreakers 會建立一個空的鍵值斷路器快取。
+func newKeyedBreakers(o *Options) *keyedBreakers {
+	return &keyedBreakers{
+		options: o,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get 會取得 key 所對應的斷路器，要是尚未存在就惰性建立一個新的，並視需要淘汰最久未使用的鍵值。
+func (k *keyedBreakers) get(key string) *Breaker {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if el, ok := k.items[key]; ok {
+		k.order.MoveToFront(el)
+		return el.Value.(*keyedBreakerEntry).breaker
+	}
+
+	b := newBreaker(k.options, k.options.Name+":"+key)
+	defaultRegistry.register(b)
+	k.items[key] = k.order.PushFront(&keyedBreakerEntry{key: key, breaker: b})
+
+	if k.order.Len() > k.options.MaxKeys {
+		oldest := k.order.Back()
+		entry := oldest.Value.(*keyedBreakerEntry)
+		k.order.Remove(oldest)
+		delete(k.items, entry.key)
+		// 連同登錄表中的項目一併淘汰，否則 `defaultRegistry` 會隨著鍵值流動無限制地增長。
+		defaultRegistry.unregister(entry.breaker.name)
+	}
+
+	return b
+}
+
 // tripper 是預設的斷路器裝置，會在連續失敗 5 次後啟動斷路器。
 func tripper(ctx *mego.Context, counts Counts) bool {
 	return counts.ConsecutiveFailures >= 5
 }
 
+// bucketCount 是滾動窗口所切分的時間段數量，每個時間段各自獨立累計成功與失敗次數。
+const bucketCount = 10
+
+// bucket 是滾動窗口中的一個時間段，記錄著該時間段內的成功與失敗次數。
+type bucket struct {
+	successes int
+	failures  int
+}
+
 // Breaker 是一個斷路器。
 type Breaker struct {
+	// mu 保護著以下所有欄位，讓斷路器可以安全地被多個請求的 Goroutine 並行存取。
+	mu sync.Mutex
 	// name 是這個斷路器的名稱。
 	name string
 	// state 是斷路器目前的狀態。
@@ -174,16 +297,168 @@ type Breaker struct {
 	options *Options
 	// counts 是斷路器的計數器。
 	counts *Counts
+	// generation 是目前的世代編號，每次重設或狀態轉換時都會遞增，用以識別並忽略過期世代的請求結果。
+	generation uint64
+	// halfOpenRequests 是半開放狀態下，目前正在通過的探測請求數。
+	halfOpenRequests int
+	// buckets 是滾動窗口的時間段陣列，每個時間段各自累計成功與失敗次數，供 `ReadyToTrip` 評估近期的失敗比率。
+	buckets [bucketCount]bucket
+	// bucketSpan 是每個時間段所涵蓋的秒數，固定為 `Interval` 的十分之一。
+	bucketSpan time.Duration
+	// bucketAt 是目前時間段的起始時間，用以判斷是否該輪轉到下一個時間段。
+	bucketAt time.Time
+	// bucketFrom 是目前時間段在 `buckets` 中的索引。
+	bucketFrom int
+}
+
+// beforeRequest 會在請求放行前檢查並視情況轉換斷路器狀態，回傳此次請求所屬的世代編號，
+// 以及此次請求是否為半開放狀態下的探測請求。要是斷路器判定此次請求應該被短路，就會回傳對應的錯誤。
+// c 可以是 `nil`（`Execute`/`Do` 等非 HTTP 呼叫即是如此）；`OnTrip` 仍然會被呼叫，
+// 自訂的 `OnTrip` 若要同時服務 HTTP 中介層與 `Execute`/`Do`，就必須能夠容忍 `ctx` 為 `nil`。
+func (b *Breaker) beforeRequest(c *mego.Context) (generation uint64, isHalfOpenProbe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 如果斷路器處於開啟狀態。
+	if b.state == StateOpen {
+		// 要是上次失敗的時間已經超過我們所設定的逾時時間，
+		// 那麼就給斷路器一次機會，回到半開放狀態。
+		if time.Since(b.lastFailure) >= b.options.Timeout {
+			b.setState(StateHalfOpen)
+		}
+	}
+	// 如果斷路器處於關閉狀態。
+	if b.state == StateClosed {
+		// 要是距離上次重設的時間已經超過了我們所設定的週期時間，
+		// 那麼就重設斷路器的所有資訊，假裝先前的錯誤不曾發生過。
+		if time.Since(b.lastInterval) >= b.options.Interval {
+			b.reset()
+		}
+		b.rotateBuckets(time.Now())
+		// 呼叫過路函式，讓開發者決定是否要開啟斷路器。`c` 在 `Execute`/`Do` 呼叫時會是 `nil`，
+		// 預設的 `tripper` 不會碰觸 `ctx` 所以能安全地共用；自訂的 `OnTrip` 若要共用同一個斷路器
+		// 同時服務 HTTP 中介層與非 HTTP 呼叫，也必須自行處理 `ctx` 為 `nil` 的情況。
+		trip := b.options.OnTrip(c, *b.counts)
+		// 要是過路函式沒有斷路，再讓 `ReadyToTrip` 依照最近滾動窗口內的失敗比率決定是否斷路。
+		if !trip && b.options.ReadyToTrip != nil {
+			trip = b.options.ReadyToTrip(b.windowCounts())
+		}
+		if trip {
+			b.setState(StateOpen)
+		}
+	}
+	// 如果經過前面那些條件，斷路器還是開啟的話就短路此次請求。
+	if b.state == StateOpen {
+		return b.generation, false, ErrOpenState
+	}
+	// 如果斷路器處於半開放狀態，探測請求數已達到 `MaxRequests` 上限，多餘的請求就直接短路。
+	if b.state == StateHalfOpen {
+		if b.halfOpenRequests >= b.options.MaxRequests {
+			return b.generation, false, ErrTooManyRequests
+		}
+		b.halfOpenRequests++
+		return b.generation, true, nil
+	}
+	return b.generation, false, nil
+}
+
+// afterRequest 會依照請求結束時的世代編號與成敗，將此次結果計入斷路器。
+// 要是此次請求放行時的世代編號已經與目前不同，表示斷路器在請求處理期間已經重設或轉換過狀態，此次結果就會被捨棄。
+func (b *Breaker) afterRequest(generation uint64, isHalfOpenProbe bool, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 要是世代已經改變，`halfOpenRequests` 早就被新的狀態轉換重設過了，這裡就不能再扣減，
+	// 否則會扣到下一個半開放世代的計數上。
+	if isHalfOpenProbe && generation == b.generation {
+		b.halfOpenRequests--
+	}
+	if generation != b.generation {
+		return
+	}
+	if failed {
+		b.fail()
+		return
+	}
+	b.success()
+}
+
+// setState 會將斷路器轉換至新的狀態，遞增世代編號並觸發 `OnStateChange`。
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	from := b.state
+	b.state = s
+	b.generation++
+	// 每次進入半開放狀態都是全新的一輪探測，必須把上一輪殘留的探測計數歸零，
+	// 否則可能導致下一輪探測一開始就被 `ErrTooManyRequests` 擋下而永遠無法復歸。
+	// 連續成功次數也一併歸零，讓回到關閉狀態所需的 `MaxRequests` 次連續成功只計算這一輪的探測結果。
+	if s == StateHalfOpen {
+		b.halfOpenRequests = 0
+		b.counts.ConsecutiveSuccesses = 0
+	}
+	if b.options.OnStateChange != nil {
+		b.options.OnStateChange(b.name, from, s)
+	}
+}
+
+// rotateBuckets 會依照經過的時間，將過期的時間段清空並輪轉到目前所在的時間段，呼叫此方法前必須持有 `mu`。
+func (b *Breaker) rotateBuckets(now time.Time) {
+	if b.bucketSpan <= 0 {
+		return
+	}
+	steps := int(now.Sub(b.bucketAt) / b.bucketSpan)
+	if steps <= 0 {
+		return
+	}
+	if steps >= bucketCount {
+		b.buckets = [bucketCount]bucket{}
+	} else {
+		for i := 1; i <= steps; i++ {
+			b.buckets[(b.bucketFrom+i)%bucketCount] = bucket{}
+		}
+	}
+	b.bucketFrom = (b.bucketFrom + steps) % bucketCount
+	b.bucketAt = b.bucketAt.Add(time.Duration(steps) * b.bucketSpan)
+}
+
+// windowCounts 會將滾動窗口內所有時間段的成功與失敗次數彙總成 `Counts`，呼叫此方法前必須持有 `mu`。
+func (b *Breaker) windowCounts() Counts {
+	var wc Counts
+	for _, bk := range b.buckets {
+		wc.TotalSuccesses += bk.successes
+		wc.TotalFailures += bk.failures
+	}
+	wc.Requests = wc.TotalSuccesses + wc.TotalFailures
+	return wc
 }
 
 // Open 會直接開啟斷路器拒絕接下來的請求。
+// 這會把 `lastFailure` 訂為現在，讓開啟狀態確實維持滿一個 `Timeout`，
+// 否則下一個進來的請求會依照早已過期的 `lastFailure` 立刻將斷路器轉為半開放狀態，使這次手動開啟形同虛設。
 func (b *Breaker) Open() {
-	b.state = StateOpen
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastFailure = time.Now()
+	b.setState(StateOpen)
 }
 
 // Close 會直接關閉斷路器並允許接下來的請求。
+// 這會連同計次、探測計數與滾動窗口資訊一併清空，
+// 否則下一個進來的請求會依照手動關閉前殘留的計次（例如早已達標的連續失敗數）讓 `OnTrip` 立刻重新斷路。
 func (b *Breaker) Close() {
-	b.state = StateClosed
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clearCounts()
+	b.setState(StateClosed)
+}
+
+// Reset 會直接重設斷路器的所有計次與狀態，等同於週期到期時的自動復歸。
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
 }
 
 // Name 能夠取得斷路器的名稱。
@@ -193,40 +468,219 @@ func (b *Breaker) Name() string {
 
 // State 能夠取得斷路器的目前狀態。
 func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	return b.state
 }
 
 // Counts 能夠取得斷路器的計數狀態。
 func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	return *b.counts
 }
 
-// fail 會追加失敗次數。
+// Execute 會透過斷路器執行 fn，讓非 HTTP 的依賴（例如資料庫查詢、gRPC 呼叫、第三方 SDK）也能共用
+// 斷路器的狀態機與計數器。斷路器開啟時會立即回傳 `ErrOpenState`；半開放狀態下超過 `MaxRequests`
+// 的探測請求則回傳 `ErrTooManyRequests`。要是 fn 發生 panic，會先被計為一次失敗，再重新拋出原本的 panic。
+// 若此方法所屬的斷路器同時也透過 `New` 掛載為某條路由的中介層，不要在該路由處理函式內對同一個實例
+// 呼叫 `Execute`/`Do`（見 `NewBreaker` 的說明）；請改用透過 `NewBreaker` 另外建立的獨立斷路器。
+func (b *Breaker) Execute(fn func() (interface{}, error)) (interface{}, error) {
+	generation, isHalfOpenProbe, err := b.beforeRequest(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b.afterRequest(generation, isHalfOpenProbe, true)
+			panic(r)
+		}
+	}()
+
+	result, err := fn()
+	b.afterRequest(generation, isHalfOpenProbe, err != nil)
+	return result, err
+}
+
+// Do 是 `Execute` 的精簡版本，適合不需要回傳值、只在意是否發生錯誤的呼叫。
+func (b *Breaker) Do(fn func() error) error {
+	_, err := b.Execute(func() (interface{}, error) {
+		return nil, fn()
+	})
+	return err
+}
+
+// fail 會追加失敗次數，呼叫此方法前必須持有 `mu`。
 func (b *Breaker) fail() {
 	b.lastFailure = time.Now()
+	b.counts.Requests++
 	b.counts.TotalFailures++
 	b.counts.ConsecutiveFailures++
 	b.counts.ConsecutiveSuccesses = 0
+	b.rotateBuckets(b.lastFailure)
+	b.buckets[b.bucketFrom].failures++
 	// 如果失敗的時候，斷路器處於半開放狀態，那麼就回歸開放狀態拒絕所有請求。
-	if b.State() == StateHalfOpen {
-		b.state = StateOpen
+	if b.state == StateHalfOpen {
+		b.setState(StateOpen)
 	}
 }
 
-// success 會追加成功次數。
+// success 會追加成功次數，呼叫此方法前必須持有 `mu`。
 func (b *Breaker) success() {
+	now := time.Now()
+	b.counts.Requests++
 	b.counts.TotalSuccesses++
 	b.counts.ConsecutiveSuccesses++
 	b.counts.ConsecutiveFailures = 0
-	// 如果成功的時候，斷路器處於半開放狀態，那麼就重設斷路器的所有資訊。
-	if b.State() == StateHalfOpen {
+	b.rotateBuckets(now)
+	b.buckets[b.bucketFrom].successes++
+	// 如果成功的時候，斷路器處於半開放狀態，且連續成功次數已經達到 `MaxRequests`，才重設斷路器並回到關閉狀態。
+	if b.state == StateHalfOpen && b.counts.ConsecutiveSuccesses >= b.options.MaxRequests {
 		b.reset()
 	}
 }
 
-// reset 會重設斷路器的資訊。
-func (b *Breaker) reset() {
+// clearCounts 會清空計次、探測計數與滾動窗口資訊，呼叫此方法前必須持有 `mu`。
+func (b *Breaker) clearCounts() {
 	b.counts = &Counts{}
+	b.halfOpenRequests = 0
+	b.buckets = [bucketCount]bucket{}
+	b.bucketFrom = 0
+	b.bucketAt = time.Now()
+	b.generation++
+}
+
+// reset 會重設斷路器的資訊，呼叫此方法前必須持有 `mu`。
+func (b *Breaker) reset() {
+	from := b.state
+	b.clearCounts()
 	b.lastInterval = time.Now()
 	b.state = StateClosed
+	if from != StateClosed && b.options.OnStateChange != nil {
+		b.options.OnStateChange(b.name, from, StateClosed)
+	}
+}
+
+// breakerSnapshot 是斷路器在某個時間點的狀態快照，供管理介面輸出成 JSON。
+type breakerSnapshot struct {
+	Name              string        `json:"name"`
+	State             string        `json:"state"`
+	Counts            Counts        `json:"counts"`
+	LastFailure       time.Time     `json:"lastFailure"`
+	TimeUntilHalfOpen time.Duration `json:"timeUntilHalfOpen"`
+}
+
+// snapshot 能夠取得斷路器目前狀態的快照。
+func (b *Breaker) snapshot() breakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := breakerSnapshot{
+		Name:        b.name,
+		State:       b.state.String(),
+		Counts:      *b.counts,
+		LastFailure: b.lastFailure,
+	}
+	// 只有在開啟狀態下，距離半開放才有意義；其餘狀態一律回傳零值。
+	if b.state == StateOpen {
+		if remaining := b.options.Timeout - time.Since(b.lastFailure); remaining > 0 {
+			s.TimeUntilHalfOpen = remaining
+		}
+	}
+	return s
+}
+
+// Registry 會依照名稱索引所有透過 New 建立的斷路器，讓 AdminHandler 得以查詢與操作它們。
+type Registry struct {
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// defaultRegistry 是套件層級的預設登錄表，每個透過 New 建立的斷路器都會自動註冊於此。
+var defaultRegistry = &Registry{breakers: map[string]*Breaker{}}
+
+// register 會將斷路器以其名稱註冊進登錄表。未指定 `Name` 的斷路器會由 `applyDefaults` 賦予獨立的
+// 預設名稱，因此同名的斷路器通常代表開發者刻意共用一個名稱，此時新的註冊會覆蓋先前的那一個。
+func (r *Registry) register(b *Breaker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakers[b.name] = b
+}
+
+// unregister 會將指定名稱的斷路器自登錄表移除，供 `keyedBreakers` 在 LRU 淘汰鍵值斷路器時呼叫，
+// 避免登錄表隨著鍵值分流斷路器的流動而無限制地增長。
+func (r *Registry) unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.breakers, name)
+}
+
+// Get 能夠依照名稱取得已註冊的斷路器，若不存在則 `ok` 會是 `false`。
+func (r *Registry) Get(name string) (b *Breaker, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok = r.breakers[name]
+	return
+}
+
+// All 能夠取得登錄表中所有已註冊的斷路器。
+func (r *Registry) All() []*Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bs := make([]*Breaker, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		bs = append(bs, b)
+	}
+	return bs
+}
+
+// snapshots 能夠取得登錄表中所有斷路器目前狀態的快照。
+func (r *Registry) snapshots() []breakerSnapshot {
+	bs := r.All()
+	ss := make([]breakerSnapshot, 0, len(bs))
+	for _, b := range bs {
+		ss = append(ss, b.snapshot())
+	}
+	return ss
+}
+
+// AdminHandler 會回傳一個可掛載於任意路徑的管理用處理器，供操作人員查詢與操控所有透過 New 建立的斷路器。
+// 以 GET 請求會列出每個斷路器目前的名稱、狀態、計次狀態、最後失敗時間，以及距離進入半開放狀態還有多久；
+// 以 POST 請求呼叫 `/{name}/open`、`/{name}/close`、`/{name}/reset`，則分別對應驅動該斷路器的
+// `Open`、`Close`、`Reset`。
+func AdminHandler() mego.HandlerFunc {
+	return func(c *mego.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.JSON(http.StatusOK, defaultRegistry.snapshots())
+			return
+		}
+		if c.Request.Method != http.MethodPost {
+			c.AbortWithError(http.StatusMethodNotAllowed, ErrBreakerNotFound)
+			return
+		}
+		// 取出路徑最後的兩段，分別作為斷路器名稱與要執行的動作，讓此處理器可以掛載在任意路徑之下。
+		segments := strings.Split(strings.Trim(c.Request.URL.Path, "/"), "/")
+		if len(segments) < 2 {
+			c.AbortWithError(http.StatusNotFound, ErrBreakerNotFound)
+			return
+		}
+		name, action := segments[len(segments)-2], segments[len(segments)-1]
+		b, ok := defaultRegistry.Get(name)
+		if !ok {
+			c.AbortWithError(http.StatusNotFound, ErrBreakerNotFound)
+			return
+		}
+		switch action {
+		case "open":
+			b.Open()
+		case "close":
+			b.Close()
+		case "reset":
+			b.Reset()
+		default:
+			c.AbortWithError(http.StatusNotFound, ErrBreakerNotFound)
+			return
+		}
+		c.JSON(http.StatusOK, b.snapshot())
+	}
 }
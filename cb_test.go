@@ -0,0 +1,214 @@
+package cb
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-mego/mego"
+)
+
+// TestBreakerConcurrentAccess 會從大量 Goroutine 同時呼叫斷路器中介層，觸發狀態的反覆開啟與復歸，
+// 搭配 `go test -race` 確保 `Breaker` 的內部狀態在高並發下不會發生資料競爭。
+func TestBreakerConcurrentAccess(t *testing.T) {
+	e := mego.New()
+
+	var calls int64
+	b := New(&Options{
+		Interval:    time.Millisecond * 5,
+		Timeout:     time.Millisecond * 5,
+		MaxRequests: 3,
+		// 只要最近一次結果是失敗就斷路，讓每三次一次的失敗確實能把斷路器開啟。
+		OnTrip: func(ctx *mego.Context, counts Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+	})
+	e.GET("/flaky", b, func(c *mego.Context) {
+		// 每三次請求中製造一次失敗，藉此反覆觸發斷路器的開啟與半開放。
+		if atomic.AddInt64(&calls, 1)%3 == 0 {
+			c.String(http.StatusInternalServerError, "fail")
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(server.URL + "/flaky")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+// TestReadyToTripRollingWindow 確認 `ReadyToTrip` 會依照滾動窗口內彙總的失敗比率，在預設的 `tripper`
+// 達到連續失敗 5 次之前就把斷路器開啟，且逾時後一次成功的半開放探測能讓斷路器回到關閉狀態。
+// 此測試中窗口內的計次與總計次相同，並不區分兩者。
+func TestReadyToTripRollingWindow(t *testing.T) {
+	breaker := newBreaker(&Options{
+		Interval:    time.Millisecond * 20,
+		Timeout:     time.Millisecond * 20,
+		MaxRequests: 1,
+		OnTrip:      tripper,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.Requests >= 4 && counts.TotalFailures*10 >= counts.Requests*6
+		},
+	}, "rolling-window-test")
+
+	// 連續五次失敗：前四次先把窗口內的失敗計次堆到門檻，第五次呼叫時 `ReadyToTrip` 才會依照
+	// 窗口彙總結果判定已達 60% 失敗率而斷路。
+	for i := 0; i < 5; i++ {
+		_ = breaker.Do(func() error { return errors.New("boom") })
+	}
+	if breaker.State() != StateOpen {
+		t.Fatalf("expected breaker to be open after rolling-window failures, got %s", breaker.State())
+	}
+
+	// 等候窗口與逾時都過期，讓舊的失敗淡出滾動窗口。
+	time.Sleep(time.Millisecond * 40)
+	if err := breaker.Do(func() error { return nil }); err != nil {
+		t.Fatalf("expected breaker to allow request after window expired, got %v", err)
+	}
+	if breaker.State() != StateClosed {
+		t.Fatalf("expected breaker to be closed after a successful probe, got %s", breaker.State())
+	}
+}
+
+// TestRegistryAdminHandler 確認 `New` 建立的斷路器會以其名稱註冊於 `defaultRegistry`，
+// 且透過 `AdminHandler` POST 到 open/close 能確實驅動對應的狀態轉換，關閉後的計次為零。
+func TestRegistryAdminHandler(t *testing.T) {
+	e := mego.New()
+
+	b := New(&Options{Name: "admin-test", MaxRequests: 1})
+	e.GET("/guarded", b, func(c *mego.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	e.Any("/admin/*action", AdminHandler())
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/admin/admin-test/open", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	br, ok := defaultRegistry.Get("admin-test")
+	if !ok || br.State() != StateOpen {
+		t.Fatalf("expected breaker %q to be open after admin open", "admin-test")
+	}
+
+	resp, err = http.Get(server.URL + "/guarded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected guarded route to be short-circuited while open, got status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(server.URL+"/admin/admin-test/close", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	br, ok = defaultRegistry.Get("admin-test")
+	if !ok || br.State() != StateClosed {
+		t.Fatalf("expected breaker %q to be closed after admin close", "admin-test")
+	}
+	if counts := br.Counts(); counts.ConsecutiveFailures != 0 {
+		t.Fatalf("expected counts to be cleared after admin close, got %+v", counts)
+	}
+}
+
+// TestExecuteAndDo 確認 `Execute`/`Do` 能在不經過 HTTP 路由的情況下，
+// 依然正確地計次、短路並於 panic 時重新拋出。
+func TestExecuteAndDo(t *testing.T) {
+	breaker := NewBreaker(&Options{
+		Name:        "execute-test",
+		MaxRequests: 1,
+		OnTrip: func(ctx *mego.Context, counts Counts) bool {
+			return counts.ConsecutiveFailures >= 2
+		},
+	})
+
+	// `OnTrip` 是在放行前依照*先前*的計次評估的，所以連續兩次失敗只會把 `ConsecutiveFailures` 堆到 2，
+	// 真正觸發斷路是在第三次呼叫放行前看到這個計次時——該次呼叫會直接被 `ErrOpenState` 短路。
+	for i := 0; i < 3; i++ {
+		err := breaker.Do(func() error {
+			return errors.New("boom")
+		})
+		if err == nil {
+			t.Fatalf("expected error from failing call")
+		}
+	}
+	if breaker.State() != StateOpen {
+		t.Fatalf("expected breaker to open after consecutive failures via Do, got %s", breaker.State())
+	}
+
+	if err := breaker.Do(func() error { return nil }); err != ErrOpenState {
+		t.Fatalf("expected ErrOpenState while open, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic to propagate through Execute")
+			}
+		}()
+		breaker.Reset()
+		_, _ = breaker.Execute(func() (interface{}, error) {
+			panic("boom")
+		})
+	}()
+}
+
+// TestNewKeyedEviction 確認 `NewKeyed` 會依鍵值各自建立斷路器並註冊於登錄表，
+// 且超過 `MaxKeys` 時，最久未使用的鍵值斷路器會從登錄表中一併被移除。
+func TestNewKeyedEviction(t *testing.T) {
+	e := mego.New()
+
+	b := NewKeyed(func(c *mego.Context) string {
+		return c.Request.URL.Query().Get("tenant")
+	}, &Options{Name: "keyed-test", MaxKeys: 2, MaxRequests: 1})
+	e.GET("/tenant", b, func(c *mego.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	for _, tenant := range []string{"a", "b", "c"} {
+		resp, err := http.Get(server.URL + "/tenant?tenant=" + tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if _, ok := defaultRegistry.Get("keyed-test:a"); ok {
+		t.Fatalf("expected least-recently-used keyed breaker to be evicted from registry")
+	}
+	if _, ok := defaultRegistry.Get("keyed-test:b"); !ok {
+		t.Fatalf("expected keyed breaker %q to still be registered", "keyed-test:b")
+	}
+	if _, ok := defaultRegistry.Get("keyed-test:c"); !ok {
+		t.Fatalf("expected keyed breaker %q to still be registered", "keyed-test:c")
+	}
+}